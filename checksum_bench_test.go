@@ -0,0 +1,30 @@
+package recordio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func benchmarkWrite(b *testing.B, kind ChecksumKind, size int) {
+	rec := bytes.Repeat([]byte("x"), size)
+	w := Writer{IO: bytesSink{}, Checksum: kind}
+	b.SetBytes(int64(size))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := w.Write(rec); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// bytesSink discards writes without growing an ever-larger buffer, so the
+// benchmark measures header/checksum overhead rather than allocation.
+type bytesSink struct{}
+
+func (bytesSink) Write(p []byte) (int, error) { return len(p), nil }
+
+func BenchmarkWriteCRC32IEEE_1KB(b *testing.B) { benchmarkWrite(b, CRC32IEEE, 1024) }
+func BenchmarkWriteCRC32C_1KB(b *testing.B)    { benchmarkWrite(b, CRC32C, 1024) }
+
+func BenchmarkWriteCRC32IEEE_1MB(b *testing.B) { benchmarkWrite(b, CRC32IEEE, 1<<20) }
+func BenchmarkWriteCRC32C_1MB(b *testing.B)    { benchmarkWrite(b, CRC32C, 1<<20) }
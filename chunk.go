@@ -0,0 +1,340 @@
+package recordio
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+
+	"github.com/golang/snappy"
+)
+
+// Codec identifies how a chunk's body is compressed.
+type Codec byte
+
+const (
+	// CodecNone stores the chunk body uncompressed.
+	CodecNone Codec = iota
+	// CodecSnappy compresses the chunk body with snappy.
+	CodecSnappy
+	// CodecGzip compresses the chunk body with gzip.
+	CodecGzip
+)
+
+// ErrUnknownCodec is returned when a chunk header references a codec this
+// package does not know how to decompress.
+var ErrUnknownCodec = errors.New("unknown codec")
+
+// chunkHeader precedes every chunk: how many records it holds, the
+// uncompressed and compressed sizes of its body, the codec used to compress
+// it, and a CRC over the compressed bytes.
+type chunkHeader struct {
+	codec      Codec
+	numRecords uint32
+	rawLen     uint32
+	compLen    uint32
+	crc        uint32
+}
+
+const chunkHeaderSize = 1 + 4 + 4 + 4 + 4
+
+func putChunkHeader(dst []byte, h chunkHeader) {
+	dst[0] = byte(h.codec)
+	binary.LittleEndian.PutUint32(dst[1:], h.numRecords)
+	binary.LittleEndian.PutUint32(dst[5:], h.rawLen)
+	binary.LittleEndian.PutUint32(dst[9:], h.compLen)
+	binary.LittleEndian.PutUint32(dst[13:], h.crc)
+}
+
+func getChunkHeader(src []byte) chunkHeader {
+	return chunkHeader{
+		codec:      Codec(src[0]),
+		numRecords: binary.LittleEndian.Uint32(src[1:]),
+		rawLen:     binary.LittleEndian.Uint32(src[5:]),
+		compLen:    binary.LittleEndian.Uint32(src[9:]),
+		crc:        binary.LittleEndian.Uint32(src[13:]),
+	}
+}
+
+// ChunkOptions configures a ChunkWriter.
+type ChunkOptions struct {
+	// Codec compresses each chunk's body before it is written.
+	Codec Codec
+	// MaxRecords caps the number of records per chunk, 0 means unlimited.
+	MaxRecords int
+	// MaxBytes caps the uncompressed size of a chunk, 0 means unlimited. A
+	// single record larger than MaxBytes still gets a chunk of its own.
+	MaxBytes int
+}
+
+// ChunkWriter groups records into chunks, each compressed as a whole and
+// prefixed with a chunkHeader, so that readers can later seek directly to
+// the chunk holding a given record instead of scanning every record.
+type ChunkWriter struct {
+	IO   io.Writer
+	Opts ChunkOptions
+
+	buf        bytes.Buffer
+	numRecords int
+}
+
+// NewChunkWriter returns a ChunkWriter that flushes chunks to w according to opts.
+func NewChunkWriter(w io.Writer, opts ChunkOptions) *ChunkWriter {
+	return &ChunkWriter{IO: w, Opts: opts}
+}
+
+// Write buffers b as the next record of the current chunk, flushing the
+// chunk first if adding b would exceed the configured limits.
+func (w *ChunkWriter) Write(b []byte) (int, error) {
+	if w.numRecords > 0 && w.exceeds(len(b)) {
+		if err := w.Flush(); err != nil {
+			return 0, err
+		}
+	}
+	rw := Writer{IO: &w.buf}
+	if _, err := rw.Write(b); err != nil {
+		return 0, err
+	}
+	w.numRecords++
+	return len(b), nil
+}
+
+func (w *ChunkWriter) exceeds(next int) bool {
+	if w.Opts.MaxRecords > 0 && w.numRecords >= w.Opts.MaxRecords {
+		return true
+	}
+	if w.Opts.MaxBytes > 0 && w.buf.Len()+recordHeaderSize+next > w.Opts.MaxBytes {
+		return true
+	}
+	return false
+}
+
+// Flush compresses and writes the current chunk, if it has any buffered
+// records, and resets the writer to start a new chunk.
+func (w *ChunkWriter) Flush() error {
+	if w.numRecords == 0 {
+		return nil
+	}
+	raw := w.buf.Bytes()
+	comp, err := compressChunk(w.Opts.Codec, raw)
+	if err != nil {
+		return err
+	}
+	var hb [chunkHeaderSize]byte
+	putChunkHeader(hb[:], chunkHeader{
+		codec:      w.Opts.Codec,
+		numRecords: uint32(w.numRecords),
+		rawLen:     uint32(len(raw)),
+		compLen:    uint32(len(comp)),
+		crc:        crc32.ChecksumIEEE(comp),
+	})
+	if _, err := w.IO.Write(hb[:]); err != nil {
+		return err
+	}
+	if _, err := w.IO.Write(comp); err != nil {
+		return err
+	}
+	w.buf.Reset()
+	w.numRecords = 0
+	return nil
+}
+
+// Close flushes any buffered records. It does not close the underlying writer.
+func (w *ChunkWriter) Close() error {
+	return w.Flush()
+}
+
+func compressChunk(codec Codec, raw []byte) ([]byte, error) {
+	switch codec {
+	case CodecNone:
+		return raw, nil
+	case CodecSnappy:
+		return snappy.Encode(nil, raw), nil
+	case CodecGzip:
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		if _, err := gw.Write(raw); err != nil {
+			return nil, err
+		}
+		if err := gw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, ErrUnknownCodec
+	}
+}
+
+func decompressChunk(codec Codec, comp []byte, rawLen int) ([]byte, error) {
+	switch codec {
+	case CodecNone:
+		return comp, nil
+	case CodecSnappy:
+		return snappy.Decode(make([]byte, 0, rawLen), comp)
+	case CodecGzip:
+		gr, err := gzip.NewReader(bytes.NewReader(comp))
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		buf := bytes.NewBuffer(make([]byte, 0, rawLen))
+		if _, err := io.Copy(buf, gr); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, ErrUnknownCodec
+	}
+}
+
+// chunkIndexEntry records where a chunk starts in the file and which
+// records it covers.
+type chunkIndexEntry struct {
+	offset      int64
+	firstRecord int
+	numRecords  int
+}
+
+// Index maps record numbers to the chunk that contains them, so a
+// RangeScanner can seek directly to the right chunk instead of scanning the
+// whole file.
+type Index struct {
+	chunks []chunkIndexEntry
+}
+
+// LoadIndex scans r from its current position, building an Index of chunk
+// offsets and record counts without decompressing any chunk body.
+func LoadIndex(r io.ReadSeeker) (*Index, error) {
+	idx := &Index{}
+	var recordNum int
+	for {
+		offset, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		var hb [chunkHeaderSize]byte
+		if _, err := io.ReadFull(r, hb[:]); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		h := getChunkHeader(hb[:])
+		idx.chunks = append(idx.chunks, chunkIndexEntry{
+			offset:      offset,
+			firstRecord: recordNum,
+			numRecords:  int(h.numRecords),
+		})
+		recordNum += int(h.numRecords)
+		if _, err := r.Seek(int64(h.compLen), io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+	return idx, nil
+}
+
+// NumRecords returns the total number of records covered by the index.
+func (idx *Index) NumRecords() int {
+	if len(idx.chunks) == 0 {
+		return 0
+	}
+	last := idx.chunks[len(idx.chunks)-1]
+	return last.firstRecord + last.numRecords
+}
+
+// chunkFor returns the position in idx.chunks of the chunk containing
+// record n, or -1 if n is out of range.
+func (idx *Index) chunkFor(n int) int {
+	for i, c := range idx.chunks {
+		if n >= c.firstRecord && n < c.firstRecord+c.numRecords {
+			return i
+		}
+	}
+	return -1
+}
+
+// RangeScanner yields records [start, end) of a chunked file, decompressing
+// only the chunks that overlap the requested range. This lets multiple
+// RangeScanners over disjoint ranges read the same file in parallel.
+type RangeScanner struct {
+	r   io.ReaderAt
+	idx *Index
+	end int
+
+	next    int
+	chunkAt int
+	loaded  [][]byte
+	record  []byte
+	err     error
+}
+
+// NewRangeScanner returns a RangeScanner over records [start, end) of the
+// chunked file described by idx, read through r.
+func NewRangeScanner(r io.ReaderAt, idx *Index, start, end int) *RangeScanner {
+	return &RangeScanner{r: r, idx: idx, end: end, next: start, chunkAt: idx.chunkFor(start)}
+}
+
+// Scan advances to the next record in range. It returns false once the
+// range is exhausted or an error occurs; call Err to distinguish the two.
+func (s *RangeScanner) Scan() bool {
+	if s.err != nil || s.next >= s.end {
+		return false
+	}
+	for len(s.loaded) == 0 {
+		if s.chunkAt < 0 || s.chunkAt >= len(s.idx.chunks) {
+			return false
+		}
+		c := s.idx.chunks[s.chunkAt]
+		recs, err := s.readChunk(c)
+		if err != nil {
+			s.err = err
+			return false
+		}
+		s.loaded = recs[s.next-c.firstRecord:]
+		s.chunkAt++
+	}
+	s.record, s.loaded = s.loaded[0], s.loaded[1:]
+	s.next++
+	return true
+}
+
+// Record returns the record produced by the most recent call to Scan.
+func (s *RangeScanner) Record() []byte {
+	return s.record
+}
+
+// Err returns the first error encountered while scanning, if any.
+func (s *RangeScanner) Err() error {
+	return s.err
+}
+
+func (s *RangeScanner) readChunk(c chunkIndexEntry) ([][]byte, error) {
+	var hb [chunkHeaderSize]byte
+	if _, err := s.r.ReadAt(hb[:], c.offset); err != nil {
+		return nil, err
+	}
+	h := getChunkHeader(hb[:])
+	comp := make([]byte, h.compLen)
+	if _, err := s.r.ReadAt(comp, c.offset+chunkHeaderSize); err != nil {
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(comp) != h.crc {
+		return nil, ErrChecksumFailed
+	}
+	raw, err := decompressChunk(h.codec, comp, int(h.rawLen))
+	if err != nil {
+		return nil, err
+	}
+	cr := Reader{IO: bytes.NewReader(raw)}
+	recs := make([][]byte, 0, h.numRecords)
+	for i := uint32(0); i < h.numRecords; i++ {
+		rec, err := cr.Next(nil)
+		if err != nil {
+			return nil, err
+		}
+		recs = append(recs, rec)
+	}
+	return recs, nil
+}
@@ -0,0 +1,63 @@
+package recordio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestChunkWriterRangeScannerRoundTrip(t *testing.T) {
+	for _, codec := range []Codec{CodecNone, CodecSnappy, CodecGzip} {
+		t.Run(codecName(codec), func(t *testing.T) {
+			var buf bytes.Buffer
+			cw := NewChunkWriter(&buf, ChunkOptions{Codec: codec, MaxRecords: 2})
+			want := [][]byte{[]byte("a"), []byte("bb"), []byte("ccc"), []byte("dddd"), []byte("e")}
+			for _, rec := range want {
+				if _, err := cw.Write(rec); err != nil {
+					t.Fatalf("Write(%q): %v", rec, err)
+				}
+			}
+			if err := cw.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+
+			data := buf.Bytes()
+			idx, err := LoadIndex(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("LoadIndex: %v", err)
+			}
+			if got, wantN := idx.NumRecords(), len(want); got != wantN {
+				t.Fatalf("NumRecords() = %d, want %d", got, wantN)
+			}
+
+			sc := NewRangeScanner(bytes.NewReader(data), idx, 1, 4)
+			var got [][]byte
+			for sc.Scan() {
+				got = append(got, append([]byte(nil), sc.Record()...))
+			}
+			if err := sc.Err(); err != nil {
+				t.Fatalf("Scan: %v", err)
+			}
+			if len(got) != 3 {
+				t.Fatalf("got %d records, want 3", len(got))
+			}
+			for i, rec := range got {
+				if !bytes.Equal(rec, want[i+1]) {
+					t.Fatalf("record %d = %q, want %q", i+1, rec, want[i+1])
+				}
+			}
+		})
+	}
+}
+
+func codecName(c Codec) string {
+	switch c {
+	case CodecNone:
+		return "none"
+	case CodecSnappy:
+		return "snappy"
+	case CodecGzip:
+		return "gzip"
+	default:
+		return "unknown"
+	}
+}
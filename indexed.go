@@ -0,0 +1,184 @@
+package recordio
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+)
+
+// indexMagic identifies the trailer IndexedWriter appends, distinguishing
+// an indexed file's footer from an ordinary recordio file.
+const indexMagic uint32 = 0x7265696e
+
+// indexEntrySize is the size of one footer entry: offset, length and crc.
+const indexEntrySize = 8 + 4 + 4
+
+// trailerSize is the size of the fixed trailer following the footer: the
+// footer's length in bytes, and the magic.
+const trailerSize = 4 + 4
+
+// indexEntry records where one record's body lives in the file, so
+// IndexedReader.ReadAt can read it directly without parsing a header.
+type indexEntry struct {
+	offset uint64
+	length uint32
+	crc    uint32
+}
+
+func putIndexEntry(dst []byte, e indexEntry) {
+	binary.LittleEndian.PutUint64(dst, e.offset)
+	binary.LittleEndian.PutUint32(dst[8:], e.length)
+	binary.LittleEndian.PutUint32(dst[12:], e.crc)
+}
+
+func getIndexEntry(src []byte) indexEntry {
+	return indexEntry{
+		offset: binary.LittleEndian.Uint64(src),
+		length: binary.LittleEndian.Uint32(src[8:]),
+		crc:    binary.LittleEndian.Uint32(src[12:]),
+	}
+}
+
+// IndexedWriter wraps Writer, remembering the position of every record
+// written so that Close can append a footer describing them. The result is
+// a write-once file that can later be opened with OpenIndexed for O(1)
+// random access, without a sidecar index.
+type IndexedWriter struct {
+	IO io.Writer
+	// Checksum selects the algorithm used for each record header; see
+	// Writer.Checksum.
+	Checksum ChecksumKind
+
+	offset  uint64
+	entries []indexEntry
+}
+
+// NewIndexedWriter returns an IndexedWriter that writes records to w and
+// appends a footer to w when Close is called.
+func NewIndexedWriter(w io.Writer) *IndexedWriter {
+	return &IndexedWriter{IO: w}
+}
+
+// Write appends a record and records its position for the footer.
+func (w *IndexedWriter) Write(b []byte) (int, error) {
+	bodyOffset := w.offset + uint64(recordHeaderSize)
+	rw := Writer{IO: w.IO, Checksum: w.Checksum}
+	n, err := rw.Write(b)
+	if err != nil {
+		return n, err
+	}
+	w.entries = append(w.entries, indexEntry{
+		offset: bodyOffset,
+		length: uint32(len(b)),
+		crc:    crc32.ChecksumIEEE(b),
+	})
+	w.offset = bodyOffset + uint64(len(b))
+	return n, nil
+}
+
+// Close appends the footer: one indexEntry per record written, followed by
+// a fixed trailer recording the footer's length and a magic number. It does
+// not close the underlying writer.
+func (w *IndexedWriter) Close() error {
+	footer := make([]byte, len(w.entries)*indexEntrySize)
+	for i, e := range w.entries {
+		putIndexEntry(footer[i*indexEntrySize:], e)
+	}
+	if _, err := w.IO.Write(footer); err != nil {
+		return err
+	}
+	var trailer [trailerSize]byte
+	binary.LittleEndian.PutUint32(trailer[:], uint32(len(footer)))
+	binary.LittleEndian.PutUint32(trailer[4:], indexMagic)
+	_, err := w.IO.Write(trailer[:])
+	return err
+}
+
+// eagerEntries is how many footer entries OpenIndexed reads alongside the
+// trailer in its first read, on the assumption that most indexed files are
+// small. Larger files fall back to a second read for the rest of the
+// footer.
+const eagerEntries = 15
+
+// IndexedReader provides O(1) random access to the records of a file
+// written by IndexedWriter, using the footer IndexedWriter.Close appended
+// instead of scanning records from the start of the file.
+type IndexedReader struct {
+	r       io.ReaderAt
+	entries []indexEntry
+}
+
+// OpenIndexed reads the footer appended by IndexedWriter.Close from the
+// last bytes of a file of the given size and returns an IndexedReader for
+// random access to its records. It reads the trailer plus eagerEntries
+// footer entries in a single call, only issuing a second read if the
+// footer turns out to be larger than that.
+func OpenIndexed(r io.ReaderAt, size int64) (*IndexedReader, error) {
+	if size < trailerSize {
+		return nil, errors.New("recordio: file too small for an index trailer")
+	}
+	eager := int64(trailerSize + eagerEntries*indexEntrySize)
+	if eager > size {
+		eager = size
+	}
+	tail := make([]byte, eager)
+	if _, err := r.ReadAt(tail, size-eager); err != nil {
+		return nil, err
+	}
+	trailer := tail[len(tail)-trailerSize:]
+	footerLen := int64(binary.LittleEndian.Uint32(trailer))
+	magic := binary.LittleEndian.Uint32(trailer[4:])
+	if magic != indexMagic {
+		return nil, errors.New("recordio: bad index trailer magic")
+	}
+	footerStart := size - trailerSize - footerLen
+	if footerStart < 0 {
+		return nil, errors.New("recordio: index trailer length exceeds file size")
+	}
+
+	have := tail[:len(tail)-trailerSize]
+	var footer []byte
+	if int64(len(have)) >= footerLen {
+		footer = have[int64(len(have))-footerLen:]
+	} else {
+		footer = make([]byte, footerLen)
+		if _, err := r.ReadAt(footer, footerStart); err != nil {
+			return nil, err
+		}
+	}
+
+	entries := make([]indexEntry, footerLen/indexEntrySize)
+	for i := range entries {
+		entries[i] = getIndexEntry(footer[i*indexEntrySize:])
+	}
+	return &IndexedReader{r: r, entries: entries}, nil
+}
+
+// NumRecords returns the number of records in the indexed file.
+func (ir *IndexedReader) NumRecords() int {
+	return len(ir.entries)
+}
+
+// ReadAt reads record recordNum directly, without touching any other
+// record, reusing buf's memory when it is large enough. It returns an
+// error if recordNum is out of range or the record's checksum does not
+// match.
+func (ir *IndexedReader) ReadAt(recordNum int, buf []byte) ([]byte, error) {
+	if recordNum < 0 || recordNum >= len(ir.entries) {
+		return nil, errors.New("recordio: record number out of range")
+	}
+	e := ir.entries[recordNum]
+	if int(e.length) > len(buf) {
+		buf = make([]byte, e.length)
+	} else {
+		buf = buf[:e.length]
+	}
+	if _, err := ir.r.ReadAt(buf, int64(e.offset)); err != nil {
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(buf) != e.crc {
+		return nil, ErrChecksumFailed
+	}
+	return buf, nil
+}
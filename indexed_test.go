@@ -0,0 +1,78 @@
+package recordio
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestIndexedWriterReaderRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewIndexedWriter(&buf)
+	want := [][]byte{[]byte("a"), []byte("bb"), []byte("ccc")}
+	for _, rec := range want {
+		if _, err := w.Write(rec); err != nil {
+			t.Fatalf("Write(%q): %v", rec, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	data := buf.Bytes()
+	ir, err := OpenIndexed(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("OpenIndexed: %v", err)
+	}
+	if got, wantN := ir.NumRecords(), len(want); got != wantN {
+		t.Fatalf("NumRecords() = %d, want %d", got, wantN)
+	}
+
+	// Read out of order to exercise true random access.
+	for _, n := range []int{2, 0, 1} {
+		got, err := ir.ReadAt(n, nil)
+		if err != nil {
+			t.Fatalf("ReadAt(%d): %v", n, err)
+		}
+		if !bytes.Equal(got, want[n]) {
+			t.Fatalf("ReadAt(%d) = %q, want %q", n, got, want[n])
+		}
+	}
+
+	if _, err := ir.ReadAt(len(want), nil); err == nil {
+		t.Fatalf("ReadAt(out of range) = nil error, want one")
+	}
+}
+
+// TestIndexedWriterReaderManyRecords exercises OpenIndexed's fallback to a
+// second read when the footer is bigger than the eager read covers.
+func TestIndexedWriterReaderManyRecords(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewIndexedWriter(&buf)
+	const n = eagerEntries * 3
+	for i := 0; i < n; i++ {
+		if _, err := w.Write([]byte{byte(i)}); err != nil {
+			t.Fatalf("Write(%d): %v", i, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	data := buf.Bytes()
+	ir, err := OpenIndexed(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("OpenIndexed: %v", err)
+	}
+	if got, want := ir.NumRecords(), n; got != want {
+		t.Fatalf("NumRecords() = %d, want %d", got, want)
+	}
+	for i := 0; i < n; i++ {
+		got, err := ir.ReadAt(i, nil)
+		if err != nil {
+			t.Fatalf("ReadAt(%d): %v", i, err)
+		}
+		if len(got) != 1 || got[0] != byte(i) {
+			t.Fatalf("ReadAt(%d) = %v, want [%d]", i, got, byte(i))
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package recordio
+
+import (
+	"bufio"
+	"io"
+)
+
+// LiveReader reads records from a recordio stream that a separate writer
+// process may still be appending to. Reader.Next treats a truncated header
+// or body as io.ErrUnexpectedEOF, which is indistinguishable from real
+// corruption; LiveReader instead buffers partial reads and reports them as
+// "not ready", so a caller can retry once more bytes have been written.
+type LiveReader struct {
+	rdr *bufio.Reader
+
+	offset int64 // bytes consumed from rdr that belong to completed records
+
+	hdrBuf  recordHeaderBytes
+	hdrLen  int
+	haveHdr bool
+	hdr     recordHeader
+
+	bodyBuf []byte
+	bodyLen int
+
+	rec []byte
+	err error
+}
+
+// NewLiveReader returns a LiveReader reading from r.
+func NewLiveReader(r io.Reader) *LiveReader {
+	return &LiveReader{rdr: bufio.NewReader(r)}
+}
+
+// Next attempts to read the next record, returning true on success; the
+// record is then available from Record. It returns false when the stream
+// ends cleanly at a record boundary (Err returns io.EOF and Ready returns
+// false), a real error occurs such as a checksum mismatch (Err returns it),
+// or the next record has not been fully written yet (Err returns io.EOF and
+// Ready returns true). Callers should retry after the last case once more
+// bytes arrive.
+func (r *LiveReader) Next() bool {
+	r.err = nil
+	if !r.haveHdr {
+		if !r.fill(r.hdrBuf[:], &r.hdrLen) {
+			return false
+		}
+		r.hdr = getHeader(r.hdrBuf[:])
+		if r.hdr.magic != recordMagic {
+			r.err = ErrBadMagic
+			r.hdrLen = 0
+			return false
+		}
+		r.haveHdr = true
+		if cap(r.bodyBuf) < int(r.hdr.bodyLen) {
+			r.bodyBuf = make([]byte, r.hdr.bodyLen)
+		}
+		r.bodyBuf = r.bodyBuf[:r.hdr.bodyLen]
+		r.bodyLen = 0
+	}
+	if !r.fill(r.bodyBuf, &r.bodyLen) {
+		return false
+	}
+	if r.hdr.bodyCRC != ChecksumKind(r.hdr.format).checksum(r.bodyBuf) {
+		r.err = ErrChecksumFailed
+		return false
+	}
+	r.rec = r.bodyBuf
+	r.offset += int64(len(r.hdrBuf)) + int64(len(r.bodyBuf))
+	r.haveHdr, r.hdrLen = false, 0
+	r.bodyLen = 0
+	return true
+}
+
+// fill reads into buf[*n:], advancing *n as bytes arrive, so that a short
+// read can be resumed on the next call instead of losing its progress. It
+// returns true once buf has been completely filled.
+func (r *LiveReader) fill(buf []byte, n *int) bool {
+	for *n < len(buf) {
+		m, err := r.rdr.Read(buf[*n:])
+		*n += m
+		if err != nil {
+			if err == io.EOF && *n == len(buf) {
+				return true
+			}
+			if err != io.EOF {
+				r.err = err
+			} else if *n < len(buf) {
+				r.err = io.EOF
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// Record returns the record produced by the most recent successful call to Next.
+func (r *LiveReader) Record() []byte {
+	return r.rec
+}
+
+// Err returns the error, if any, from the most recent call to Next.
+func (r *LiveReader) Err() error {
+	return r.err
+}
+
+// Ready reports whether Err is io.EOF because a record is mid-write: some
+// bytes of its header or body have already arrived but not all of them. It
+// returns false when the stream simply has no more data past the last
+// complete record, i.e. there is nothing in flight to wait for yet.
+func (r *LiveReader) Ready() bool {
+	return r.err == io.EOF && (r.hdrLen > 0 || r.bodyLen > 0)
+}
+
+// Offset returns the number of bytes consumed from the underlying reader
+// that belong to fully read records. Callers can checkpoint this value and
+// later resume tailing from the same position.
+func (r *LiveReader) Offset() int64 {
+	return r.offset
+}
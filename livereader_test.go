@@ -0,0 +1,104 @@
+package recordio
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestLiveReaderRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w := Writer{IO: &buf}
+	want := [][]byte{[]byte("first"), []byte("second"), []byte("third")}
+	for _, rec := range want {
+		if _, err := w.Write(rec); err != nil {
+			t.Fatalf("Write(%q): %v", rec, err)
+		}
+	}
+
+	lr := NewLiveReader(bytes.NewReader(buf.Bytes()))
+	for i, want := range want {
+		if !lr.Next() {
+			t.Fatalf("Next #%d returned false, err=%v", i, lr.Err())
+		}
+		if got := lr.Record(); !bytes.Equal(got, want) {
+			t.Fatalf("Record #%d = %q, want %q", i, got, want)
+		}
+	}
+	if lr.Next() {
+		t.Fatalf("Next at end returned true")
+	}
+	if err := lr.Err(); err != io.EOF {
+		t.Fatalf("Err() at end = %v, want io.EOF", err)
+	}
+	if lr.Ready() {
+		t.Fatalf("Ready() at a clean boundary = true, want false")
+	}
+	if got, want := lr.Offset(), int64(buf.Len()); got != want {
+		t.Fatalf("Offset() = %d, want %d", got, want)
+	}
+}
+
+// TestLiveReaderTailsPartialWrites simulates a writer still appending to the
+// file: Next must report not-ready on a truncated record instead of a real
+// error, and must pick the record up once the rest of it arrives, without
+// losing the bytes already delivered for it across the two calls.
+func TestLiveReaderTailsPartialWrites(t *testing.T) {
+	var full bytes.Buffer
+	w := Writer{IO: &full}
+	if _, err := w.Write([]byte("first")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("second, a longer record")); err != nil {
+		t.Fatal(err)
+	}
+	data := full.Bytes()
+
+	live := &bytes.Buffer{}
+	lr := NewLiveReader(readerFunc(func(p []byte) (int, error) {
+		return live.Read(p)
+	}))
+
+	firstRecordSize := recordHeaderSize + len("first")
+
+	// Deliver only part of the first record's header.
+	live.Write(data[:recordHeaderSize-3])
+	if lr.Next() {
+		t.Fatalf("Next succeeded before the first record's header fully arrived")
+	}
+	if err := lr.Err(); err != io.EOF || !lr.Ready() {
+		t.Fatalf("Err()=%v Ready()=%v, want io.EOF and Ready()=true", err, lr.Ready())
+	}
+
+	live.Write(data[recordHeaderSize-3 : firstRecordSize])
+	if !lr.Next() {
+		t.Fatalf("Next failed once the first record fully arrived: %v", lr.Err())
+	}
+	if got, want := string(lr.Record()), "first"; got != want {
+		t.Fatalf("Record() = %q, want %q", got, want)
+	}
+
+	split := firstRecordSize + recordHeaderSize + 5
+	live.Write(data[firstRecordSize:split])
+
+	// The second record is only partially written; Next must wait instead
+	// of misreading a stale length left over from the first record.
+	if lr.Next() {
+		t.Fatalf("Next succeeded before the second record fully arrived")
+	}
+	if !lr.Ready() {
+		t.Fatalf("Ready() = false while the second record is mid-write")
+	}
+
+	live.Write(data[split:])
+	if !lr.Next() {
+		t.Fatalf("Next failed once the second record fully arrived: %v", lr.Err())
+	}
+	if got, want := string(lr.Record()), "second, a longer record"; got != want {
+		t.Fatalf("Record() = %q, want %q", got, want)
+	}
+}
+
+type readerFunc func(p []byte) (int, error)
+
+func (f readerFunc) Read(p []byte) (int, error) { return f(p) }
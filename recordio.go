@@ -2,9 +2,9 @@
 package recordio
 
 import (
+	"bufio"
 	"encoding/binary"
 	"errors"
-	"hash/crc32"
 	"io"
 )
 
@@ -15,25 +15,49 @@ var (
 	ErrChecksumFailed = errors.New("checksum failed")
 	// ErrRecordTooLarge returned when reading a record larger than allowed, this often indicates data corrupted
 	ErrRecordTooLarge = errors.New("record to large")
+	// ErrBadMagic indicates a record header's magic number did not match,
+	// meaning the stream is corrupted or the reader is misaligned with it
+	ErrBadMagic = errors.New("bad magic")
 )
 
+// recordMagic prefixes every non-legacy record header, letting Reader.Resync
+// tell a real header apart from arbitrary bytes left by corruption.
+const recordMagic uint32 = 0x823a56e8
+
+// recordHeaderSize is the on-disk size of a magic-framed header: magic,
+// format byte, bodyLen and bodyCRC.
+const recordHeaderSize = 4 + 1 + 4 + 4
+
+// legacyHeaderSize is the size of the pre-magic header format: bodyLen and
+// bodyCRC only.
+const legacyHeaderSize = 4 + 4
+
 type recordHeader struct {
+	magic   uint32
+	format  byte
 	bodyLen uint32
 	bodyCRC uint32
 }
 
-type recordHeaderBytes [8]byte
+type recordHeaderBytes [recordHeaderSize]byte
+
+type legacyHeaderBytes [legacyHeaderSize]byte
 
 // Writer writes record to a basic io.Writer with headers
 type Writer struct {
 	IO io.Writer
+	// Checksum selects the algorithm used to checksum each record's body.
+	// The zero value is CRC32IEEE, for compatibility with files written
+	// before checksums were pluggable.
+	Checksum ChecksumKind
 }
 
 func (w *Writer) Write(b []byte) (int, error) {
 	var hb recordHeaderBytes
 	putHeader(hb[:], recordHeader{
+		format:  byte(w.Checksum),
 		bodyLen: uint32(len(b)),
-		bodyCRC: crc32.ChecksumIEEE(b),
+		bodyCRC: w.Checksum.checksum(b),
 	})
 
 	if n, err := w.IO.Write(hb[:]); err != nil || n < len(hb) {
@@ -49,17 +73,20 @@ func (w *Writer) Write(b []byte) (int, error) {
 type Reader struct {
 	IO            io.Reader
 	MaxRecordSize int
+	// Legacy makes Next read the pre-magic 8-byte header format (bodyLen
+	// and bodyCRC only), for files written before magic numbers were
+	// introduced. Resync is not available in this mode.
+	Legacy bool
 }
 
 // Next reads next record from reader, if size of next record smaller than len(buf),
 // memory of buf will be used in the returning slice, otherwise, new memory will be allocated.
 // when there's no next record, io.EOF will be returned.
 func (r *Reader) Next(buf []byte) ([]byte, error) {
-	var hb recordHeaderBytes
-	if _, err := io.ReadFull(r.IO, hb[:]); err != nil {
+	h, err := r.nextHeader()
+	if err != nil {
 		return nil, err
 	}
-	h := getHeader(hb[:])
 	if r.MaxRecordSize > 0 && int(h.bodyLen) > r.MaxRecordSize {
 		return nil, ErrRecordTooLarge
 	}
@@ -71,20 +98,114 @@ func (r *Reader) Next(buf []byte) ([]byte, error) {
 	if _, err := io.ReadFull(r.IO, buf); err != nil {
 		return nil, err
 	}
-	if h.bodyCRC != crc32.ChecksumIEEE(buf) {
+	if h.bodyCRC != ChecksumKind(h.format).checksum(buf) {
 		return nil, ErrChecksumFailed
 	}
 	return buf, nil
 }
 
+func (r *Reader) nextHeader() (recordHeader, error) {
+	if r.Legacy {
+		var hb legacyHeaderBytes
+		if _, err := io.ReadFull(r.IO, hb[:]); err != nil {
+			return recordHeader{}, err
+		}
+		return getLegacyHeader(hb[:]), nil
+	}
+	// Peek rather than read the header so a bad magic leaves the bytes
+	// buffered instead of permanently consumed: Resync needs them still
+	// in the stream to scan for a later, valid header.
+	br := r.bufReader()
+	hb, err := br.Peek(recordHeaderSize)
+	if err != nil {
+		if err == io.EOF && len(hb) > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		return recordHeader{}, err
+	}
+	h := getHeader(hb)
+	if h.magic != recordMagic {
+		return recordHeader{}, ErrBadMagic
+	}
+	if _, err := br.Discard(recordHeaderSize); err != nil {
+		return recordHeader{}, err
+	}
+	return h, nil
+}
+
+// bufReader returns r.IO as a *bufio.Reader, wrapping it once on first use
+// and storing the wrapper back into IO so nextHeader and Resync share the
+// same buffered, not-yet-consumed bytes.
+func (r *Reader) bufReader() *bufio.Reader {
+	if br, ok := r.IO.(*bufio.Reader); ok {
+		return br
+	}
+	br := bufio.NewReader(r.IO)
+	r.IO = br
+	return br
+}
+
+// Resync recovers from a corrupted or truncated section of the stream after
+// Next has returned ErrChecksumFailed or ErrBadMagic. It scans the
+// underlying reader byte by byte for the next header whose magic number,
+// length and checksum are all internally consistent, and positions the
+// Reader so the following call to Next resumes from there. Resync is not
+// supported when Legacy is set, since pre-magic headers have nothing to
+// search for.
+func (r *Reader) Resync() error {
+	if r.Legacy {
+		return errors.New("recordio: Resync requires magic-framed headers")
+	}
+	bufSize := recordHeaderSize + r.MaxRecordSize
+	if r.MaxRecordSize <= 0 {
+		bufSize = recordHeaderSize + 64*1024
+	}
+	// Grow through bufReader's existing wrapper, if any, rather than
+	// rewrapping r.IO directly: a bad magic leaves its header bytes
+	// buffered but undiscarded, and rewrapping r.IO would skip past them.
+	br := r.bufReader()
+	if br.Size() < bufSize {
+		br = bufio.NewReaderSize(br, bufSize)
+		r.IO = br
+	}
+	for {
+		hb, err := br.Peek(recordHeaderSize)
+		if err != nil {
+			return err
+		}
+		if binary.LittleEndian.Uint32(hb) == recordMagic {
+			h := getHeader(hb)
+			if body, err := br.Peek(recordHeaderSize + int(h.bodyLen)); err == nil {
+				if ChecksumKind(h.format).checksum(body[recordHeaderSize:]) == h.bodyCRC {
+					return nil
+				}
+			}
+		}
+		if _, err := br.Discard(1); err != nil {
+			return err
+		}
+	}
+}
+
 func putHeader(dst []byte, header recordHeader) {
-	binary.LittleEndian.PutUint32(dst, header.bodyLen)
-	binary.LittleEndian.PutUint32(dst[4:], header.bodyCRC)
+	binary.LittleEndian.PutUint32(dst, recordMagic)
+	dst[4] = header.format
+	binary.LittleEndian.PutUint32(dst[5:], header.bodyLen)
+	binary.LittleEndian.PutUint32(dst[9:], header.bodyCRC)
 }
 
 func getHeader(src []byte) recordHeader {
-	var h recordHeader
-	h.bodyLen = binary.LittleEndian.Uint32(src)
-	h.bodyCRC = binary.LittleEndian.Uint32(src[4:])
-	return h
+	return recordHeader{
+		magic:   binary.LittleEndian.Uint32(src),
+		format:  src[4],
+		bodyLen: binary.LittleEndian.Uint32(src[5:]),
+		bodyCRC: binary.LittleEndian.Uint32(src[9:]),
+	}
+}
+
+func getLegacyHeader(src []byte) recordHeader {
+	return recordHeader{
+		bodyLen: binary.LittleEndian.Uint32(src),
+		bodyCRC: binary.LittleEndian.Uint32(src[4:]),
+	}
 }
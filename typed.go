@@ -0,0 +1,184 @@
+package recordio
+
+import (
+	"encoding/json"
+	"io"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// RecordsMetadata is written as the first record of a file by ProtoWriter
+// and JSONWriter, describing the records that follow -- analogous to
+// Riegeli's file metadata record.
+type RecordsMetadata struct {
+	// Tags holds arbitrary user-supplied key/value metadata, e.g.
+	// {"type": "my.pkg.Event"}.
+	Tags map[string]string `json:"tags,omitempty"`
+	// ProtoDescriptor optionally carries the serialized FileDescriptorProto
+	// of the record type, letting readers self-describe the schema.
+	ProtoDescriptor []byte `json:"protoDescriptor,omitempty"`
+}
+
+// writeMetadataHeader marshals metadata as JSON and writes it to w as the
+// first record, unless *wroteHeader already says it has been written.
+func writeMetadataHeader(w *Writer, wroteHeader *bool, metadata RecordsMetadata) error {
+	if *wroteHeader {
+		return nil
+	}
+	hb, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(hb); err != nil {
+		return err
+	}
+	*wroteHeader = true
+	return nil
+}
+
+// readMetadataHeader reads the first record from r and unmarshals it into
+// *metadata as JSON, unless *haveMeta already says it has been read.
+func readMetadataHeader(r *Reader, haveMeta *bool, metadata *RecordsMetadata) error {
+	if *haveMeta {
+		return nil
+	}
+	b, err := r.Next(nil)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, metadata); err != nil {
+		return err
+	}
+	*haveMeta = true
+	return nil
+}
+
+// ProtoWriter wraps Writer, marshaling proto.Message records and writing
+// metadata as the file's first record.
+type ProtoWriter struct {
+	Metadata RecordsMetadata
+
+	w           Writer
+	wroteHeader bool
+}
+
+// NewProtoWriter returns a ProtoWriter writing to w with the given metadata.
+func NewProtoWriter(w io.Writer, metadata RecordsMetadata) *ProtoWriter {
+	return &ProtoWriter{Metadata: metadata, w: Writer{IO: w}}
+}
+
+// Write marshals msg and appends it as the next record, writing the
+// metadata header first if this is the first call.
+func (pw *ProtoWriter) Write(msg proto.Message) error {
+	if err := writeMetadataHeader(&pw.w, &pw.wroteHeader, pw.Metadata); err != nil {
+		return err
+	}
+	b, err := proto.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = pw.w.Write(b)
+	return err
+}
+
+// ProtoReader wraps Reader, unmarshaling records as proto.Message and
+// exposing the metadata written as the file's first record.
+type ProtoReader struct {
+	r        Reader
+	metadata RecordsMetadata
+	haveMeta bool
+	buf      []byte
+}
+
+// NewProtoReader returns a ProtoReader reading from r.
+func NewProtoReader(r io.Reader) *ProtoReader {
+	return &ProtoReader{r: Reader{IO: r}}
+}
+
+// Metadata returns the RecordsMetadata written as the file's first record,
+// reading it on the first call.
+func (pr *ProtoReader) Metadata() (RecordsMetadata, error) {
+	if err := readMetadataHeader(&pr.r, &pr.haveMeta, &pr.metadata); err != nil {
+		return RecordsMetadata{}, err
+	}
+	return pr.metadata, nil
+}
+
+// Next reads the next record and unmarshals it into msg, reusing its
+// internal buffer across calls.
+func (pr *ProtoReader) Next(msg proto.Message) error {
+	if err := readMetadataHeader(&pr.r, &pr.haveMeta, &pr.metadata); err != nil {
+		return err
+	}
+	b, err := pr.r.Next(pr.buf)
+	if err != nil {
+		return err
+	}
+	pr.buf = b
+	return proto.Unmarshal(b, msg)
+}
+
+// JSONWriter wraps Writer, marshaling arbitrary values as JSON records and
+// writing metadata as the file's first record.
+type JSONWriter struct {
+	Metadata RecordsMetadata
+
+	w           Writer
+	wroteHeader bool
+}
+
+// NewJSONWriter returns a JSONWriter writing to w with the given metadata.
+func NewJSONWriter(w io.Writer, metadata RecordsMetadata) *JSONWriter {
+	return &JSONWriter{Metadata: metadata, w: Writer{IO: w}}
+}
+
+// Write marshals v as JSON and appends it as the next record, writing the
+// metadata header first if this is the first call.
+func (jw *JSONWriter) Write(v interface{}) error {
+	if err := writeMetadataHeader(&jw.w, &jw.wroteHeader, jw.Metadata); err != nil {
+		return err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = jw.w.Write(b)
+	return err
+}
+
+// JSONReader wraps Reader, unmarshaling records as JSON and exposing the
+// metadata written as the file's first record.
+type JSONReader struct {
+	r        Reader
+	metadata RecordsMetadata
+	haveMeta bool
+	buf      []byte
+}
+
+// NewJSONReader returns a JSONReader reading from r.
+func NewJSONReader(r io.Reader) *JSONReader {
+	return &JSONReader{r: Reader{IO: r}}
+}
+
+// Metadata returns the RecordsMetadata written as the file's first record,
+// reading it on the first call.
+func (jr *JSONReader) Metadata() (RecordsMetadata, error) {
+	if err := readMetadataHeader(&jr.r, &jr.haveMeta, &jr.metadata); err != nil {
+		return RecordsMetadata{}, err
+	}
+	return jr.metadata, nil
+}
+
+// Next reads the next record and unmarshals it into v, reusing its
+// internal buffer across calls.
+func (jr *JSONReader) Next(v interface{}) error {
+	if err := readMetadataHeader(&jr.r, &jr.haveMeta, &jr.metadata); err != nil {
+		return err
+	}
+	b, err := jr.r.Next(jr.buf)
+	if err != nil {
+		return err
+	}
+	jr.buf = b
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,71 @@
+package recordio
+
+import (
+	"bytes"
+	"testing"
+
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestProtoWriterReaderRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	metadata := RecordsMetadata{Tags: map[string]string{"type": "google.protobuf.StringValue"}}
+	w := NewProtoWriter(&buf, metadata)
+	want := []string{"first", "second", "third"}
+	for _, s := range want {
+		if err := w.Write(wrapperspb.String(s)); err != nil {
+			t.Fatalf("Write(%q): %v", s, err)
+		}
+	}
+
+	r := NewProtoReader(&buf)
+	gotMeta, err := r.Metadata()
+	if err != nil {
+		t.Fatalf("Metadata: %v", err)
+	}
+	if gotMeta.Tags["type"] != metadata.Tags["type"] {
+		t.Fatalf("Metadata().Tags = %v, want %v", gotMeta.Tags, metadata.Tags)
+	}
+
+	for i, want := range want {
+		var msg wrapperspb.StringValue
+		if err := r.Next(&msg); err != nil {
+			t.Fatalf("Next #%d: %v", i, err)
+		}
+		if msg.Value != want {
+			t.Fatalf("Next #%d = %q, want %q", i, msg.Value, want)
+		}
+	}
+}
+
+func TestJSONWriterReaderRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	metadata := RecordsMetadata{Tags: map[string]string{"type": "point"}}
+	w := NewJSONWriter(&buf, metadata)
+	type point struct{ X, Y int }
+	want := []point{{1, 2}, {3, 4}}
+	for _, p := range want {
+		if err := w.Write(p); err != nil {
+			t.Fatalf("Write(%v): %v", p, err)
+		}
+	}
+
+	r := NewJSONReader(&buf)
+	gotMeta, err := r.Metadata()
+	if err != nil {
+		t.Fatalf("Metadata: %v", err)
+	}
+	if gotMeta.Tags["type"] != metadata.Tags["type"] {
+		t.Fatalf("Metadata().Tags = %v, want %v", gotMeta.Tags, metadata.Tags)
+	}
+
+	for i, want := range want {
+		var got point
+		if err := r.Next(&got); err != nil {
+			t.Fatalf("Next #%d: %v", i, err)
+		}
+		if got != want {
+			t.Fatalf("Next #%d = %v, want %v", i, got, want)
+		}
+	}
+}